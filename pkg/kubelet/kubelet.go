@@ -0,0 +1,31 @@
+/*
+Copyright 2014 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"k8s.io/kubernetes/pkg/kubelet/lifecycle"
+)
+
+// registerPodAdmitHandlers wires the kubelet's built-in PodAdmitHandlers onto
+// admitHandlers, the list consulted by canAdmitPod during the sync loop. It's called once
+// from NewMainKubelet after klet.admitHandlers has been initialized.
+func (kl *Kubelet) registerPodAdmitHandlers() {
+	kl.admitHandlers.AddPodAdmitHandler(lifecycle.NewPredicateAdmitHandler(kl.getNodeAnyWay, kl.containerManager.UpdatePluginResources, kl.podManager.GetMirrorPodByPod))
+	// windowsResourceAdmitHandler is a no-op on non-Windows nodes; it's registered
+	// unconditionally so a binary built for Windows always enforces it.
+	kl.admitHandlers.AddPodAdmitHandler(lifecycle.NewWindowsResourceAdmitHandler())
+}
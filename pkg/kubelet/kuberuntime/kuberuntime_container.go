@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kuberuntime
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+	kubecontainer "k8s.io/kubernetes/pkg/kubelet/container"
+
+	"k8s.io/klog"
+)
+
+// containerStatusByCreated is a slice of kubecontainer.ContainerStatus that implements
+// sort.Interface to sort by created timestamp, newest first.
+type containerStatusByCreated []*kubecontainer.ContainerStatus
+
+func (s containerStatusByCreated) Len() int      { return len(s) }
+func (s containerStatusByCreated) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s containerStatusByCreated) Less(i, j int) bool {
+	return s[i].CreatedAt.After(s[j].CreatedAt)
+}
+
+// getPodContainerStatuses gets all containers' statuses for the pod.
+func (m *kubeGenericRuntimeManager) getPodContainerStatuses(uid types.UID, name, namespace string) ([]*kubecontainer.ContainerStatus, error) {
+	// Select all containers of the given pod.
+	containers, err := m.runtimeService.ListContainers(&runtimeapi.ContainerFilter{
+		LabelSelector: map[string]string{types.KubernetesPodUIDLabel: string(uid)},
+	})
+	if err != nil {
+		klog.Errorf("ListContainers error for pod %q: %v", name, err)
+		return nil, err
+	}
+
+	statuses := make([]*kubecontainer.ContainerStatus, len(containers))
+	for i, c := range containers {
+		resp, err := m.runtimeService.ContainerStatus(c.Id)
+		if err != nil {
+			klog.Errorf("ContainerStatus for %q error: %v", c.Id, err)
+			return nil, err
+		}
+		statuses[i] = m.toKubeContainerStatus(resp)
+	}
+
+	sort.Sort(containerStatusByCreated(statuses))
+	return statuses, nil
+}
+
+// toKubeContainerStatus converts runtimeapi.ContainerStatus to kubecontainer.ContainerStatus.
+// Effective resources are surfaced via the platform-specific toKubeContainerResources (see
+// kuberuntime_container_windows.go) so PodStatus reflects what the runtime actually applied,
+// not just what was requested.
+func (m *kubeGenericRuntimeManager) toKubeContainerStatus(status *runtimeapi.ContainerStatus) *kubecontainer.ContainerStatus {
+	annotatedInfo := getContainerInfoFromAnnotations(status.Annotations)
+	labeledInfo := getContainerInfoFromLabels(status.Labels)
+	cStatus := &kubecontainer.ContainerStatus{
+		ID: kubecontainer.ContainerID{
+			Type: m.runtimeName,
+			ID:   status.Id,
+		},
+		Name:         labeledInfo.ContainerName,
+		Image:        status.Image.Image,
+		ImageID:      status.ImageRef,
+		Hash:         annotatedInfo.Hash,
+		RestartCount: annotatedInfo.RestartCount,
+		State:        toKubeContainerState(status.State),
+		CreatedAt:    time.Unix(0, status.CreatedAt),
+		Reason:       status.Reason,
+		Message:      status.Message,
+		ExitCode:     int(status.ExitCode),
+		Resources:    m.toKubeContainerResources(status.Resources),
+	}
+
+	if status.State != runtimeapi.ContainerState_CONTAINER_CREATED {
+		cStatus.StartedAt = time.Unix(0, status.StartedAt)
+	}
+	if status.State == runtimeapi.ContainerState_CONTAINER_EXITED {
+		cStatus.FinishedAt = time.Unix(0, status.FinishedAt)
+	}
+
+	return cStatus
+}
@@ -20,18 +20,102 @@ package kuberuntime
 
 import (
 	"fmt"
+	"strconv"
+
 	"github.com/docker/docker/pkg/sysinfo"
 
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 	kubefeatures "k8s.io/kubernetes/pkg/features"
 	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+	kubecontainer "k8s.io/kubernetes/pkg/kubelet/container"
 	"k8s.io/kubernetes/pkg/securitycontext"
 
 	"k8s.io/klog"
 )
 
+// windowsCPUCountAnnotation and windowsCPUPercentAnnotation let a pod request explicit
+// Windows Server Container CPU controls instead of relying solely on the millicpu-derived
+// defaults computed below. They are only honored for non-Hyper-V isolated containers, and
+// are subject to the same CpuCount > CpuShares > CpuMaximum precedence Docker enforces.
+const (
+	windowsCPUCountAnnotation   = "windows.kubernetes.io/cpu-count"
+	windowsCPUPercentAnnotation = "windows.kubernetes.io/cpu-percent"
+)
+
+// windowsCPUCountFromAnnotations returns the CpuCount requested via windowsCPUCountAnnotation,
+// in range [1, sysinfo.NumCPU()], or 0 if the annotation is absent. It returns an error if the
+// annotation is not a valid integer, is below 1, or exceeds the host's CPU count.
+func windowsCPUCountFromAnnotations(annotations map[string]string) (int64, error) {
+	val, ok := annotations[windowsCPUCountAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	cpuCount, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation value %q: %v", windowsCPUCountAnnotation, val, err)
+	}
+	if cpuCount < 1 {
+		return 0, fmt.Errorf("%s annotation must be at least 1, got %d", windowsCPUCountAnnotation, cpuCount)
+	}
+	if maxCPU := int64(sysinfo.NumCPU()); cpuCount > maxCPU {
+		return 0, fmt.Errorf("%s annotation (%d) exceeds the number of CPUs available on this node (%d)", windowsCPUCountAnnotation, cpuCount, maxCPU)
+	}
+	return cpuCount, nil
+}
+
+// windowsCPUMaximumFromAnnotations returns the CpuMaximum (CPU percent * 100) requested via
+// windowsCPUPercentAnnotation, in range [1, 10000], or 0 if the annotation is absent. It
+// returns an error if the annotation is not a valid positive integer or exceeds 100%.
+func windowsCPUMaximumFromAnnotations(annotations map[string]string) (int64, error) {
+	val, ok := annotations[windowsCPUPercentAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	cpuPercent, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation value %q: %v", windowsCPUPercentAnnotation, val, err)
+	}
+	if cpuPercent < 1 {
+		return 0, fmt.Errorf("%s annotation must be at least 1, got %d", windowsCPUPercentAnnotation, cpuPercent)
+	}
+	cpuMaximum := cpuPercent * 100
+	if cpuMaximum > 10000 {
+		return 0, fmt.Errorf("%s annotation (%d) exceeds 100%%", windowsCPUPercentAnnotation, cpuPercent)
+	}
+	return cpuMaximum, nil
+}
+
+// toKubeContainerResources maps the effective Windows CPU/memory resources reported by the CRI
+// runtime's ContainerStatus back into a kubecontainer.ContainerResources, mirroring the Linux
+// translation in kuberuntime_container_linux.go. getPodContainerStatuses's toKubeContainerStatus
+// calls this so Windows container statuses surface effective resources in PodStatus, which
+// in-place pod vertical scaling relies on to detect when a resize has converged.
+func (m *kubeGenericRuntimeManager) toKubeContainerResources(statusResources *runtimeapi.ContainerResources) *kubecontainer.ContainerResources {
+	windowsResources := statusResources.GetWindows()
+	if windowsResources == nil {
+		return nil
+	}
+
+	var cpuLimit, memLimit *resource.Quantity
+	if windowsResources.CpuMaximum > 0 {
+		milliCPU := windowsResources.CpuMaximum * int64(sysinfo.NumCPU()) * 1000 / 10000
+		cpuLimit = resource.NewMilliQuantity(milliCPU, resource.DecimalSI)
+	} else if windowsResources.CpuCount > 0 {
+		cpuLimit = resource.NewQuantity(windowsResources.CpuCount, resource.DecimalSI)
+	}
+	if windowsResources.MemoryLimitInBytes > 0 {
+		memLimit = resource.NewQuantity(windowsResources.MemoryLimitInBytes, resource.BinarySI)
+	}
+
+	return &kubecontainer.ContainerResources{
+		CPULimit:    cpuLimit,
+		MemoryLimit: memLimit,
+	}
+}
+
 // applyPlatformSpecificContainerConfig applies platform specific configurations to runtimeapi.ContainerConfig.
 func (m *kubeGenericRuntimeManager) applyPlatformSpecificContainerConfig(config *runtimeapi.ContainerConfig, container *v1.Container, pod *v1.Pod, uid *int64, username string) error {
 	windowsConfig, err := m.generateWindowsContainerConfig(container, pod, uid, username)
@@ -43,13 +127,13 @@ func (m *kubeGenericRuntimeManager) applyPlatformSpecificContainerConfig(config
 	return nil
 }
 
-// generateWindowsContainerConfig generates windows container config for kubelet runtime v1.
-// Refer https://github.com/kubernetes/community/blob/master/contributors/design-proposals/node/cri-windows.md.
-func (m *kubeGenericRuntimeManager) generateWindowsContainerConfig(container *v1.Container, pod *v1.Pod, uid *int64, username string) (*runtimeapi.WindowsContainerConfig, error) {
-	wc := &runtimeapi.WindowsContainerConfig{
-		Resources:       &runtimeapi.WindowsContainerResources{},
-		SecurityContext: &runtimeapi.WindowsContainerSecurityContext{},
-	}
+// calculateWindowsResources computes the WindowsContainerResources for a container given its
+// current spec, applying the millicpu-to-CpuMaximum/CpuCount derivation, the explicit
+// per-pod CPU hints, and the CpuCount > CpuShares > CpuMaximum precedence/clamping rules.
+// generateWindowsContainerConfig and updateContainerResources both call this so that a resize
+// produces the identical resource struct that creating the container with the new spec would.
+func (m *kubeGenericRuntimeManager) calculateWindowsResources(container *v1.Container, pod *v1.Pod) (*runtimeapi.WindowsContainerResources, error) {
+	resources := &runtimeapi.WindowsContainerResources{}
 
 	cpuRequest := container.Resources.Requests.Cpu()
 	cpuLimit := container.Resources.Limits.Cpu()
@@ -62,7 +146,7 @@ func (m *kubeGenericRuntimeManager) generateWindowsContainerConfig(container *v1
 		cpuMaximum := 10000 * cpuLimit.MilliValue() / int64(sysinfo.NumCPU()) / 1000
 		if isolatedByHyperv {
 			cpuCount := int64(cpuLimit.MilliValue()+999) / 1000
-			wc.Resources.CpuCount = cpuCount
+			resources.CpuCount = cpuCount
 
 			if cpuCount != 0 {
 				cpuMaximum = cpuLimit.MilliValue() / cpuCount * 10000 / 1000
@@ -75,31 +159,52 @@ func (m *kubeGenericRuntimeManager) generateWindowsContainerConfig(container *v1
 			cpuMaximum = 10000
 		}
 
-		wc.Resources.CpuMaximum = cpuMaximum
+		resources.CpuMaximum = cpuMaximum
 	}
 
 	cpuShares := milliCPUToShares(cpuLimit.MilliValue(), isolatedByHyperv)
 	if cpuShares == 0 {
 		cpuShares = milliCPUToShares(cpuRequest.MilliValue(), isolatedByHyperv)
 	}
-	wc.Resources.CpuShares = cpuShares
+	resources.CpuShares = cpuShares
 
 	if !isolatedByHyperv {
+		// Explicit per-pod hints take precedence over the millicpu-derived values computed
+		// above; they're applied here, before the precedence enforcement below, so that a
+		// requested CpuCount/CpuMaximum still zeroes out the derived CpuShares as expected,
+		// rather than having CpuShares's own priority over CpuMaximum immediately zero the
+		// annotation's value right back out.
+		annotatedCPUCount, err := windowsCPUCountFromAnnotations(pod.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		annotatedCPUMaximum, err := windowsCPUMaximumFromAnnotations(pod.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		if annotatedCPUCount > 0 {
+			resources.CpuCount = annotatedCPUCount
+			resources.CpuShares = 0
+		} else if annotatedCPUMaximum > 0 {
+			resources.CpuMaximum = annotatedCPUMaximum
+			resources.CpuShares = 0
+		}
+
 		// The processor resource controls are mutually exclusive on
 		// Windows Server Containers, the order of precedence is
 		// CPUCount first, then CPUShares, and CPUMaximum last.
-		if wc.Resources.CpuCount > 0 {
-			if wc.Resources.CpuShares > 0 {
-				wc.Resources.CpuShares = 0
+		if resources.CpuCount > 0 {
+			if resources.CpuShares > 0 {
+				resources.CpuShares = 0
 				klog.Warningf("Mutually exclusive options: CPUCount priority > CPUShares priority on Windows Server Containers. CPUShares should be ignored")
 			}
-			if wc.Resources.CpuMaximum > 0 {
-				wc.Resources.CpuMaximum = 0
+			if resources.CpuMaximum > 0 {
+				resources.CpuMaximum = 0
 				klog.Warningf("Mutually exclusive options: CPUCount priority > CPUMaximum priority on Windows Server Containers. CPUMaximum should be ignored")
 			}
-		} else if wc.Resources.CpuShares > 0 {
-			if wc.Resources.CpuMaximum > 0 {
-				wc.Resources.CpuMaximum = 0
+		} else if resources.CpuShares > 0 {
+			if resources.CpuMaximum > 0 {
+				resources.CpuMaximum = 0
 				klog.Warningf("Mutually exclusive options: CPUShares priority > CPUMaximum priority on Windows Server Containers. CPUMaximum should be ignored")
 			}
 
@@ -108,7 +213,22 @@ func (m *kubeGenericRuntimeManager) generateWindowsContainerConfig(container *v1
 
 	memoryLimit := container.Resources.Limits.Memory().Value()
 	if memoryLimit != 0 {
-		wc.Resources.MemoryLimitInBytes = memoryLimit
+		resources.MemoryLimitInBytes = memoryLimit
+	}
+
+	return resources, nil
+}
+
+// generateWindowsContainerConfig generates windows container config for kubelet runtime v1.
+// Refer https://github.com/kubernetes/community/blob/master/contributors/design-proposals/node/cri-windows.md.
+func (m *kubeGenericRuntimeManager) generateWindowsContainerConfig(container *v1.Container, pod *v1.Pod, uid *int64, username string) (*runtimeapi.WindowsContainerConfig, error) {
+	resources, err := m.calculateWindowsResources(container, pod)
+	if err != nil {
+		return nil, err
+	}
+	wc := &runtimeapi.WindowsContainerConfig{
+		Resources:       resources,
+		SecurityContext: &runtimeapi.WindowsContainerSecurityContext{},
 	}
 
 	// setup security context
@@ -133,3 +253,19 @@ func (m *kubeGenericRuntimeManager) generateWindowsContainerConfig(container *v1
 
 	return wc, nil
 }
+
+// updateContainerResources updates a running Windows container's CpuMaximum, CpuShares,
+// CpuCount, and MemoryLimitInBytes via the CRI UpdateContainerResources RPC, without
+// recreating the container. This is the Windows counterpart the kubelet's in-place pod
+// vertical scaling resize path uses to apply a resized container spec.
+func (m *kubeGenericRuntimeManager) updateContainerResources(pod *v1.Pod, container *v1.Container, containerID kubecontainer.ContainerID) error {
+	resources, err := m.calculateWindowsResources(container, pod)
+	if err != nil {
+		return err
+	}
+	err = m.runtimeService.UpdateContainerResources(containerID.ID, resources)
+	if err != nil {
+		klog.Errorf("UpdateContainerResources %q failed: %v", containerID.String(), err)
+	}
+	return err
+}
@@ -0,0 +1,266 @@
+// +build windows
+
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kuberuntime
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/docker/docker/pkg/sysinfo"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+	kubecontainer "k8s.io/kubernetes/pkg/kubelet/container"
+)
+
+func TestWindowsCPUCountFromAnnotations(t *testing.T) {
+	maxCPU := int64(sysinfo.NumCPU())
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        int64
+		wantErr     bool
+	}{
+		{
+			name:        "annotation absent",
+			annotations: map[string]string{},
+			want:        0,
+		},
+		{
+			name:        "valid count within range",
+			annotations: map[string]string{windowsCPUCountAnnotation: "1"},
+			want:        1,
+		},
+		{
+			name:        "zero is rejected, not clamped up to 1",
+			annotations: map[string]string{windowsCPUCountAnnotation: "0"},
+			wantErr:     true,
+		},
+		{
+			name:        "negative is rejected",
+			annotations: map[string]string{windowsCPUCountAnnotation: "-1"},
+			wantErr:     true,
+		},
+		{
+			name:        "non-integer is rejected",
+			annotations: map[string]string{windowsCPUCountAnnotation: "abc"},
+			wantErr:     true,
+		},
+		{
+			name:        "exceeding host CPU count is rejected",
+			annotations: map[string]string{windowsCPUCountAnnotation: "1000000"},
+			wantErr:     true,
+		},
+		{
+			name:        "exactly the host CPU count is accepted",
+			annotations: map[string]string{windowsCPUCountAnnotation: strconv.FormatInt(maxCPU, 10)},
+			want:        maxCPU,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := windowsCPUCountFromAnnotations(tc.annotations)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("windowsCPUCountFromAnnotations() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && got != tc.want {
+				t.Errorf("windowsCPUCountFromAnnotations() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWindowsCPUMaximumFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        int64
+		wantErr     bool
+	}{
+		{
+			name:        "annotation absent",
+			annotations: map[string]string{},
+			want:        0,
+		},
+		{
+			name:        "valid percent within range",
+			annotations: map[string]string{windowsCPUPercentAnnotation: "50"},
+			want:        5000,
+		},
+		{
+			name:        "100 percent is the maximum CpuMaximum",
+			annotations: map[string]string{windowsCPUPercentAnnotation: "100"},
+			want:        10000,
+		},
+		{
+			name:        "zero is rejected, not clamped up to 1",
+			annotations: map[string]string{windowsCPUPercentAnnotation: "0"},
+			wantErr:     true,
+		},
+		{
+			name:        "negative is rejected",
+			annotations: map[string]string{windowsCPUPercentAnnotation: "-1"},
+			wantErr:     true,
+		},
+		{
+			name:        "non-integer is rejected",
+			annotations: map[string]string{windowsCPUPercentAnnotation: "abc"},
+			wantErr:     true,
+		},
+		{
+			name:        "exceeding 100 percent is rejected",
+			annotations: map[string]string{windowsCPUPercentAnnotation: "101"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := windowsCPUMaximumFromAnnotations(tc.annotations)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("windowsCPUMaximumFromAnnotations() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && got != tc.want {
+				t.Errorf("windowsCPUMaximumFromAnnotations() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func podAndContainerWithCPU(requestMilli, limitMilli int64, annotations map[string]string) (*v1.Pod, *v1.Container) {
+	resources := v1.ResourceRequirements{Requests: v1.ResourceList{}, Limits: v1.ResourceList{}}
+	if requestMilli > 0 {
+		resources.Requests[v1.ResourceCPU] = *resource.NewMilliQuantity(requestMilli, resource.DecimalSI)
+	}
+	if limitMilli > 0 {
+		resources.Limits[v1.ResourceCPU] = *resource.NewMilliQuantity(limitMilli, resource.DecimalSI)
+	}
+	container := &v1.Container{Name: "c", Resources: resources}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default", Annotations: annotations},
+		Spec:       v1.PodSpec{Containers: []v1.Container{*container}},
+	}
+	return pod, container
+}
+
+// TestCalculateWindowsResourcesPrecedence covers the CpuCount > CpuShares > CpuMaximum
+// mutual-exclusion rules for non-Hyper-V-isolated containers.
+func TestCalculateWindowsResourcesPrecedence(t *testing.T) {
+	var m kubeGenericRuntimeManager
+
+	tests := []struct {
+		name             string
+		requestMilli     int64
+		limitMilli       int64
+		annotations      map[string]string
+		wantCPUCount     int64
+		wantCPUMaximum   int64
+		cpuSharesIsZero  bool
+		cpuMaximumIsZero bool
+	}{
+		{
+			name:             "millicpu derived CpuShares zeroes derived CpuMaximum, no annotations",
+			requestMilli:     1000,
+			limitMilli:       2000,
+			cpuMaximumIsZero: true,
+		},
+		{
+			name:             "CpuCount annotation zeroes CpuShares and CpuMaximum",
+			requestMilli:     1000,
+			limitMilli:       2000,
+			annotations:      map[string]string{windowsCPUCountAnnotation: "2"},
+			wantCPUCount:     2,
+			cpuSharesIsZero:  true,
+			cpuMaximumIsZero: true,
+		},
+		{
+			name:            "CpuMaximum annotation overrides the derived value and zeroes CpuShares",
+			requestMilli:    1000,
+			limitMilli:      2000,
+			annotations:     map[string]string{windowsCPUPercentAnnotation: "75"},
+			wantCPUMaximum:  7500,
+			cpuSharesIsZero: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pod, container := podAndContainerWithCPU(tc.requestMilli, tc.limitMilli, tc.annotations)
+			resources, err := m.calculateWindowsResources(container, pod)
+			if err != nil {
+				t.Fatalf("calculateWindowsResources() error = %v", err)
+			}
+			if resources.CpuCount != tc.wantCPUCount {
+				t.Errorf("CpuCount = %d, want %d", resources.CpuCount, tc.wantCPUCount)
+			}
+			if tc.cpuSharesIsZero && resources.CpuShares != 0 {
+				t.Errorf("CpuShares = %d, want 0", resources.CpuShares)
+			} else if !tc.cpuSharesIsZero && resources.CpuShares == 0 {
+				t.Errorf("CpuShares = 0, want a non-zero millicpu-derived value")
+			}
+			if tc.wantCPUMaximum != 0 && resources.CpuMaximum != tc.wantCPUMaximum {
+				t.Errorf("CpuMaximum = %d, want %d", resources.CpuMaximum, tc.wantCPUMaximum)
+			}
+			if tc.cpuMaximumIsZero && resources.CpuMaximum != 0 {
+				t.Errorf("CpuMaximum = %d, want 0", resources.CpuMaximum)
+			}
+		})
+	}
+}
+
+// fakeContainerRuntimeService is a minimal containerRuntimeService that only records the
+// resources passed to UpdateContainerResources, for asserting what updateContainerResources
+// actually sends over the CRI RPC.
+type fakeContainerRuntimeService struct {
+	containerRuntimeService
+	updatedResources *runtimeapi.WindowsContainerResources
+}
+
+func (f *fakeContainerRuntimeService) UpdateContainerResources(containerID string, resources *runtimeapi.WindowsContainerResources) error {
+	f.updatedResources = resources
+	return nil
+}
+
+// TestCalculateWindowsResourcesResizeParity asserts that updateContainerResources, the resize
+// path, sends the CRI runtime the identical WindowsContainerResources struct that
+// generateWindowsContainerConfig, the create path, would have produced for the same container
+// spec.
+func TestCalculateWindowsResourcesResizeParity(t *testing.T) {
+	fakeRuntimeService := &fakeContainerRuntimeService{}
+	m := kubeGenericRuntimeManager{runtimeService: fakeRuntimeService}
+	pod, container := podAndContainerWithCPU(500, 1500, map[string]string{windowsCPUPercentAnnotation: "60"})
+
+	windowsConfig, err := m.generateWindowsContainerConfig(container, pod, nil, "")
+	if err != nil {
+		t.Fatalf("generateWindowsContainerConfig() error = %v", err)
+	}
+
+	if err := m.updateContainerResources(pod, container, kubecontainer.ContainerID{}); err != nil {
+		t.Fatalf("updateContainerResources() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(windowsConfig.Resources, fakeRuntimeService.updatedResources) {
+		t.Errorf("resize sent %+v to UpdateContainerResources, want the same struct create would produce: %+v", fakeRuntimeService.updatedResources, windowsConfig.Resources)
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kuberuntime
+
+import (
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// containerRuntimeService is the subset of the CRI RuntimeServiceClient that
+// kubeGenericRuntimeManager calls directly, narrowed to what this package actually uses so it
+// can be faked in tests without pulling in the full CRI client surface.
+type containerRuntimeService interface {
+	ListContainers(filter *runtimeapi.ContainerFilter) ([]*runtimeapi.Container, error)
+	ContainerStatus(containerID string) (*runtimeapi.ContainerStatus, error)
+	UpdateContainerResources(containerID string, resources *runtimeapi.WindowsContainerResources) error
+}
+
+// kubeGenericRuntimeManager implements kubecontainer.Runtime against a CRI runtime service.
+type kubeGenericRuntimeManager struct {
+	runtimeName    string
+	runtimeService containerRuntimeService
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"github.com/docker/docker/pkg/sysinfo"
+
+	"k8s.io/api/core/v1"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
+
+	"k8s.io/klog"
+)
+
+// windowsCPUCountAnnotation mirrors the annotation honored by generateWindowsContainerConfig
+// in pkg/kubelet/kuberuntime; it's read here too so infeasible requests are rejected at
+// admission instead of being silently clamped at container creation time.
+const windowsCPUCountAnnotation = "windows.kubernetes.io/cpu-count"
+
+// windowsResourceAdmitHandler rejects Windows pods whose aggregated CPU requests, or whose
+// per-container windows.kubernetes.io/cpu-count annotation, exceed what the node can actually
+// run. It is a no-op on non-Windows nodes.
+type windowsResourceAdmitHandler struct{}
+
+// NewWindowsResourceAdmitHandler returns a PodAdmitHandler enforcing Windows CPU resource
+// feasibility alongside the existing predicate admission handler.
+func NewWindowsResourceAdmitHandler() PodAdmitHandler {
+	return &windowsResourceAdmitHandler{}
+}
+
+func (w *windowsResourceAdmitHandler) Admit(attrs *PodAdmitAttributes) PodAdmitResult {
+	if runtime.GOOS != "windows" {
+		return PodAdmitResult{Admit: true}
+	}
+
+	return checkWindowsPodResourceFeasibility(attrs.Pod, int64(sysinfo.NumCPU()))
+}
+
+// checkWindowsPodResourceFeasibility is the testable core of Admit. numCPU is the number of
+// processors sysinfo reports available to a single process; on nodes with more than 64
+// processors this is capped at 64 by Windows Processor Groups (see
+// kuberuntime_container_windows.go), which is why it's threaded through explicitly rather than
+// read from sysinfo.NumCPU() here.
+func checkWindowsPodResourceFeasibility(pod *v1.Pod, numCPU int64) PodAdmitResult {
+	// calculateWindowsResources (kuberuntime_container_windows.go) only honors
+	// windowsCPUCountAnnotation for non-Hyper-V-isolated containers; a Hyper-V isolated pod with
+	// a stale or out-of-range annotation would be silently ignored at container-creation time,
+	// so don't reject it here either.
+	isolatedByHyperv := kubeletapis.ShouldIsolatedByHyperV(pod.Annotations)
+
+	var totalMilliCPU int64
+	for _, container := range pod.Spec.Containers {
+		totalMilliCPU += container.Resources.Requests.Cpu().MilliValue()
+
+		if isolatedByHyperv {
+			continue
+		}
+		if cpuLimit := container.Resources.Limits.Cpu().MilliValue(); cpuLimit > 0 && 10000*cpuLimit/numCPU/1000 < 1 {
+			// Mirrors the [1, 10000] clamp generateWindowsContainerConfig applies to CpuMaximum:
+			// on nodes with more than 64 processors, Windows Processor Groups cap a single
+			// process to 64 of them (see kuberuntime_container_windows.go), so a small CPU
+			// limit can round down to 0 here. Warn rather than reject, since the container
+			// config computation already promotes it to the 1 (0.01%) floor.
+			klog.Warningf("pod %s/%s: container %q CPU limit (%dm) rounds down to a CpuMaximum of 0 on this node's %d processors; it will be promoted to 1", pod.Namespace, pod.Name, container.Name, cpuLimit, numCPU)
+		}
+		val, ok := pod.Annotations[windowsCPUCountAnnotation]
+		if !ok {
+			continue
+		}
+		cpuCount, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return PodAdmitResult{
+				Admit:   false,
+				Reason:  "PodAdmissionFailure",
+				Message: fmt.Sprintf("invalid %s annotation value %q: %v", windowsCPUCountAnnotation, val, err),
+			}
+		}
+		if cpuCount < 1 {
+			return PodAdmitResult{
+				Admit:   false,
+				Reason:  "PodAdmissionFailure",
+				Message: fmt.Sprintf("%s annotation must be at least 1, got %d", windowsCPUCountAnnotation, cpuCount),
+			}
+		}
+		if cpuCount > numCPU {
+			return PodAdmitResult{
+				Admit:   false,
+				Reason:  "PodAdmissionFailure",
+				Message: fmt.Sprintf("container %q requests CpuCount %d which exceeds this node's %d processors", container.Name, cpuCount, numCPU),
+			}
+		}
+	}
+
+	if totalMilliCPU == 0 {
+		return PodAdmitResult{Admit: true}
+	}
+
+	if totalMilliCPU > numCPU*1000 {
+		return PodAdmitResult{
+			Admit:   false,
+			Reason:  "PodAdmissionFailure",
+			Message: fmt.Sprintf("pod's aggregated CPU request (%dm) exceeds this node's %d processors", totalMilliCPU, numCPU),
+		}
+	}
+
+	return PodAdmitResult{Admit: true}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podWithCPURequestAndAnnotation(milliCPU int64, annotations map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default", Annotations: annotations},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: "c",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU: *resource.NewMilliQuantity(milliCPU, resource.DecimalSI),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// hypervIsolationAnnotation mirrors the annotation kubeletapis.ShouldIsolatedByHyperV checks;
+// it's not exported from that package in this tree, so it's spelled out here for the test that
+// exercises the Hyper-V skip.
+const hypervIsolationAnnotation = "experimental.windows.kubernetes.io/isolation-type"
+
+func TestCheckWindowsPodResourceFeasibility(t *testing.T) {
+	tests := []struct {
+		name      string
+		pod       *v1.Pod
+		numCPU    int64
+		wantAdmit bool
+	}{
+		{
+			name:      "no CPU request is always admitted",
+			pod:       podWithCPURequestAndAnnotation(0, nil),
+			numCPU:    4,
+			wantAdmit: true,
+		},
+		{
+			name:      "aggregated request within a small node's capacity",
+			pod:       podWithCPURequestAndAnnotation(2000, nil),
+			numCPU:    4,
+			wantAdmit: true,
+		},
+		{
+			name:      "aggregated request exceeds a small node's capacity",
+			pod:       podWithCPURequestAndAnnotation(5000, nil),
+			numCPU:    4,
+			wantAdmit: false,
+		},
+		{
+			name:      "aggregated request exceeding the node by less than 1 core is rejected",
+			pod:       podWithCPURequestAndAnnotation(4500, nil),
+			numCPU:    4,
+			wantAdmit: false,
+		},
+		{
+			name:      "request just within capacity when numCPU is 128",
+			pod:       podWithCPURequestAndAnnotation(128000, nil),
+			numCPU:    128,
+			wantAdmit: true,
+		},
+		{
+			name:      "request exceeding capacity when numCPU is 128",
+			pod:       podWithCPURequestAndAnnotation(129000, nil),
+			numCPU:    128,
+			wantAdmit: false,
+		},
+		{
+			name: "small CPU limit with numCPU 128 rounds CpuMaximum down to 0 but is still admitted",
+			pod: func() *v1.Pod {
+				pod := podWithCPURequestAndAnnotation(1, nil)
+				pod.Spec.Containers[0].Resources.Limits = v1.ResourceList{
+					v1.ResourceCPU: *resource.NewMilliQuantity(1, resource.DecimalSI),
+				}
+				return pod
+			}(),
+			numCPU:    128,
+			wantAdmit: true,
+		},
+		{
+			name:      "CpuCount annotation within capacity when numCPU is 128",
+			pod:       podWithCPURequestAndAnnotation(0, map[string]string{windowsCPUCountAnnotation: "96"}),
+			numCPU:    128,
+			wantAdmit: true,
+		},
+		{
+			name:      "CpuCount annotation exceeding a >64-processor node's capacity",
+			pod:       podWithCPURequestAndAnnotation(0, map[string]string{windowsCPUCountAnnotation: "65"}),
+			numCPU:    64,
+			wantAdmit: false,
+		},
+		{
+			name:      "invalid CpuCount annotation is rejected",
+			pod:       podWithCPURequestAndAnnotation(0, map[string]string{windowsCPUCountAnnotation: "not-a-number"}),
+			numCPU:    64,
+			wantAdmit: false,
+		},
+		{
+			name:      "zero CpuCount annotation is rejected, not treated as absent",
+			pod:       podWithCPURequestAndAnnotation(0, map[string]string{windowsCPUCountAnnotation: "0"}),
+			numCPU:    64,
+			wantAdmit: false,
+		},
+		{
+			name:      "negative CpuCount annotation is rejected",
+			pod:       podWithCPURequestAndAnnotation(0, map[string]string{windowsCPUCountAnnotation: "-1"}),
+			numCPU:    64,
+			wantAdmit: false,
+		},
+		{
+			name: "out-of-range CpuCount annotation on a Hyper-V isolated pod is not enforced at admission",
+			pod: podWithCPURequestAndAnnotation(0, map[string]string{
+				windowsCPUCountAnnotation: "129",
+				hypervIsolationAnnotation: "hyperv",
+			}),
+			numCPU:    64,
+			wantAdmit: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := checkWindowsPodResourceFeasibility(tc.pod, tc.numCPU)
+			if result.Admit != tc.wantAdmit {
+				t.Errorf("checkWindowsPodResourceFeasibility() = %+v, want Admit=%v", result, tc.wantAdmit)
+			}
+		})
+	}
+}